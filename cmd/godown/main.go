@@ -0,0 +1,43 @@
+// Command godown starts a godown server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+	"github.com/namreg/godown-v2/internal/pkg/storage/disk"
+	"github.com/namreg/godown-v2/internal/pkg/storage/memory"
+)
+
+func main() {
+	storageKind := flag.String("storage", "memory", `storage backend to use: "memory" or "disk"`)
+	dir := flag.String("dir", "", `directory for the disk storage backend (required when -storage=disk)`)
+	flag.Parse()
+
+	strg, err := newStorage(*storageKind, *dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer strg.Close()
+
+	log.Printf("godown started with %s storage", *storageKind)
+	select {}
+}
+
+// newStorage builds the storage.Storage backend selected on the command
+// line.
+func newStorage(kind, dir string) (storage.Storage, error) {
+	switch kind {
+	case "memory":
+		return memory.New(nil), nil
+	case "disk":
+		if dir == "" {
+			return nil, fmt.Errorf("-dir is required when -storage=disk")
+		}
+		return disk.New(dir, disk.Options{AutoCreate: true, SyncWrites: true})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", kind)
+	}
+}
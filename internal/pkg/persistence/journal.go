@@ -0,0 +1,432 @@
+// Package persistence adds append-only-file durability on top of any
+// storage.Storage, so an in-memory instance can survive a restart without
+// switching backends.
+package persistence
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+)
+
+// FsyncPolicy controls how aggressively the Journal flushes its log file to
+// disk, mirroring Redis' appendfsync setting.
+type FsyncPolicy int
+
+const (
+	// FsyncAlways fsyncs after every single write. Safest, slowest.
+	FsyncAlways FsyncPolicy = iota
+	// FsyncEverySec fsyncs at most once per second from a background goroutine.
+	FsyncEverySec
+	// FsyncNo never fsyncs explicitly, leaving it to the OS.
+	FsyncNo
+)
+
+const (
+	opPut byte = iota + 1
+	opDel
+)
+
+// Options configures a Journal.
+type Options struct {
+	FsyncPolicy FsyncPolicy
+	// SnapshotThreshold is the log size, in bytes, above which the Journal
+	// compacts itself into a fresh snapshot. Zero disables compaction.
+	SnapshotThreshold int64
+}
+
+// Journal wraps a storage.Storage and appends a record of every mutation to
+// a log file, so the wrapped storage can be reconstructed by Replay after a
+// restart.
+type Journal struct {
+	storage.Storage
+
+	path string
+	opts Options
+
+	mu    sync.Mutex
+	f     *os.File
+	size  int64
+	dirty bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Open wraps dst with a Journal that appends to the log file at path,
+// creating it if necessary.
+func Open(path string, dst storage.Storage, opts Options) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("persistence: open journal: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("persistence: stat journal: %w", err)
+	}
+
+	j := &Journal{
+		Storage: dst,
+		path:    path,
+		opts:    opts,
+		f:       f,
+		size:    info.Size(),
+		done:    make(chan struct{}),
+	}
+	if opts.FsyncPolicy == FsyncEverySec {
+		go j.fsyncEverySecond()
+	}
+	return j, nil
+}
+
+// Put applies setter to the wrapped storage and appends a record of the
+// resulting value to the journal, or a deletion record if setter returned nil.
+func (j *Journal) Put(key storage.Key, setter storage.ValueSetter) error {
+	var result *storage.Value
+	var deleted bool
+
+	err := j.Storage.Put(key, func(old *storage.Value) (*storage.Value, error) {
+		newVal, err := setter(old)
+		if err != nil {
+			return nil, err
+		}
+		if newVal == nil {
+			deleted = true
+		} else {
+			result = newVal
+		}
+		return newVal, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if deleted {
+		return j.append(opDel, key, nil)
+	}
+	if result != nil {
+		return j.append(opPut, key, result)
+	}
+	return nil
+}
+
+// Del deletes key from the wrapped storage and appends a record of the
+// deletion to the journal.
+func (j *Journal) Del(key storage.Key) error {
+	if err := j.Storage.Del(key); err != nil {
+		return err
+	}
+	return j.append(opDel, key, nil)
+}
+
+// PutMany applies every setter to the wrapped storage under its usual
+// single-lock guarantee, then appends a record for each key that changed.
+func (j *Journal) PutMany(items map[storage.Key]storage.ValueSetter) error {
+	results := make(map[storage.Key]*storage.Value, len(items))
+	var deletedKeys []storage.Key
+
+	wrapped := make(map[storage.Key]storage.ValueSetter, len(items))
+	for key, setter := range items {
+		key, setter := key, setter
+		wrapped[key] = func(old *storage.Value) (*storage.Value, error) {
+			newVal, err := setter(old)
+			if err != nil {
+				return nil, err
+			}
+			if newVal == nil {
+				deletedKeys = append(deletedKeys, key)
+			} else {
+				results[key] = newVal
+			}
+			return newVal, nil
+		}
+	}
+
+	if err := j.Storage.PutMany(wrapped); err != nil {
+		return err
+	}
+	for _, key := range deletedKeys {
+		if err := j.append(opDel, key, nil); err != nil {
+			return err
+		}
+	}
+	for key, val := range results {
+		if err := j.append(opPut, key, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DelMany deletes every key from the wrapped storage and appends a record
+// for each one, same as repeated calls to Del.
+func (j *Journal) DelMany(keys ...storage.Key) (int, error) {
+	deleted, err := j.Storage.DelMany(keys...)
+	if err != nil {
+		return deleted, err
+	}
+	for _, key := range keys {
+		if err := j.append(opDel, key, nil); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// Close stops the background fsync goroutine, flushes and closes the log
+// file, and closes the wrapped storage.
+func (j *Journal) Close() error {
+	var err error
+	j.closeOnce.Do(func() {
+		close(j.done)
+		j.mu.Lock()
+		err = j.f.Sync()
+		if cerr := j.f.Close(); err == nil {
+			err = cerr
+		}
+		j.mu.Unlock()
+	})
+	if werr := j.Storage.Close(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// append writes one record to the log and, if the configured threshold is
+// exceeded, triggers a snapshot compaction.
+func (j *Journal) append(op byte, key storage.Key, value *storage.Value) error {
+	rec, err := encodeRecord(op, key, value)
+	if err != nil {
+		return fmt.Errorf("persistence: encode record: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	n, err := j.f.Write(rec)
+	if err != nil {
+		return fmt.Errorf("persistence: write record: %w", err)
+	}
+	j.size += int64(n)
+	j.dirty = true
+
+	if j.opts.FsyncPolicy == FsyncAlways {
+		if err := j.f.Sync(); err != nil {
+			return fmt.Errorf("persistence: fsync: %w", err)
+		}
+		j.dirty = false
+	}
+
+	if j.opts.SnapshotThreshold > 0 && j.size >= j.opts.SnapshotThreshold {
+		return j.compactLocked()
+	}
+	return nil
+}
+
+func (j *Journal) fsyncEverySecond() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.done:
+			return
+		case <-ticker.C:
+			j.mu.Lock()
+			if j.dirty {
+				j.f.Sync()
+				j.dirty = false
+			}
+			j.mu.Unlock()
+		}
+	}
+}
+
+// compactLocked rewrites the log as a fresh snapshot of the wrapped
+// storage's current contents and atomically replaces the old log with it.
+// Callers must hold j.mu; it is held for the whole operation so that writes
+// racing with a snapshot are simply serialized rather than lost.
+func (j *Journal) compactLocked() error {
+	all, err := j.Storage.All()
+	if err != nil {
+		return fmt.Errorf("persistence: compact: read storage: %w", err)
+	}
+
+	tmpPath := j.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("persistence: compact: open snapshot: %w", err)
+	}
+
+	var size int64
+	for key, val := range all {
+		rec, err := encodeRecord(opPut, key, val)
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("persistence: compact: encode: %w", err)
+		}
+		n, err := tmp.Write(rec)
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("persistence: compact: write snapshot: %w", err)
+		}
+		size += int64(n)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("persistence: compact: fsync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("persistence: compact: close snapshot: %w", err)
+	}
+
+	if err := j.f.Close(); err != nil {
+		return fmt.Errorf("persistence: compact: close log: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		return fmt.Errorf("persistence: compact: rename snapshot: %w", err)
+	}
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("persistence: compact: reopen log: %w", err)
+	}
+	j.f = f
+	j.size = size
+	j.dirty = false
+	return nil
+}
+
+// Replay reads every record from the journal file at path and applies it to
+// dst, reconstructing the state it held before a restart. Records already
+// expired by the time they're read are skipped. A partial record at the end
+// of the file (e.g. from a crash mid-write) stops the replay at the last
+// complete record instead of returning an error.
+func Replay(path string, dst storage.Storage) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("persistence: replay: open: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		op, key, ttlNano, valueBytes, err := readRecord(r)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("persistence: replay: read record: %w", err)
+		}
+
+		if ttlNano != 0 && time.Now().UnixNano() > ttlNano {
+			continue
+		}
+
+		switch op {
+		case opPut:
+			val, err := storage.DecodeValue(valueBytes)
+			if err != nil {
+				return fmt.Errorf("persistence: replay: decode value: %w", err)
+			}
+			if err := dst.Put(key, func(*storage.Value) (*storage.Value, error) { return val, nil }); err != nil {
+				return fmt.Errorf("persistence: replay: put %q: %w", key, err)
+			}
+		case opDel:
+			if err := dst.Del(key); err != nil {
+				return fmt.Errorf("persistence: replay: del %q: %w", key, err)
+			}
+		}
+	}
+}
+
+// encodeRecord builds a length-prefixed record:
+//
+//	[4 bytes total length]
+//	[1 byte op][4 bytes key length][key][8 bytes ttl unix ns][4 bytes value length][value]
+func encodeRecord(op byte, key storage.Key, value *storage.Value) ([]byte, error) {
+	var valueBytes []byte
+	var ttlNano int64
+	if value != nil {
+		data, err := storage.EncodeValue(value)
+		if err != nil {
+			return nil, err
+		}
+		valueBytes = data
+		if t, ok := value.TTL(); ok {
+			ttlNano = t.UnixNano()
+		}
+	}
+
+	body := make([]byte, 0, 1+4+len(key)+8+4+len(valueBytes))
+	body = append(body, op)
+	body = appendUint32(body, uint32(len(key)))
+	body = append(body, key...)
+	body = appendUint64(body, uint64(ttlNano))
+	body = appendUint32(body, uint32(len(valueBytes)))
+	body = append(body, valueBytes...)
+
+	rec := make([]byte, 0, 4+len(body))
+	rec = appendUint32(rec, uint32(len(body)))
+	rec = append(rec, body...)
+	return rec, nil
+}
+
+// readRecord reads one record from r. It returns io.EOF when the log ends
+// cleanly between records, and io.ErrUnexpectedEOF when it ends mid-record
+// (a truncated tail from a crash).
+func readRecord(r *bufio.Reader) (op byte, key storage.Key, ttlNano int64, value []byte, err error) {
+	var lenBuf [4]byte
+	if _, err = io.ReadFull(r, lenBuf[:]); err != nil {
+		if err == io.EOF {
+			return 0, "", 0, nil, io.EOF
+		}
+		return 0, "", 0, nil, io.ErrUnexpectedEOF
+	}
+
+	body := make([]byte, binary.BigEndian.Uint32(lenBuf[:]))
+	if _, err = io.ReadFull(r, body); err != nil {
+		return 0, "", 0, nil, io.ErrUnexpectedEOF
+	}
+
+	if len(body) < 1+4 {
+		return 0, "", 0, nil, io.ErrUnexpectedEOF
+	}
+	op = body[0]
+	keyLen := binary.BigEndian.Uint32(body[1:5])
+	rest := body[5:]
+	if uint32(len(rest)) < keyLen+8+4 {
+		return 0, "", 0, nil, io.ErrUnexpectedEOF
+	}
+	key = storage.Key(rest[:keyLen])
+	rest = rest[keyLen:]
+	ttlNano = int64(binary.BigEndian.Uint64(rest[:8]))
+	rest = rest[8:]
+	valueLen := binary.BigEndian.Uint32(rest[:4])
+	rest = rest[4:]
+	if uint32(len(rest)) < valueLen {
+		return 0, "", 0, nil, io.ErrUnexpectedEOF
+	}
+	return op, key, ttlNano, rest[:valueLen], nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], v)
+	return append(b, tmp[:]...)
+}
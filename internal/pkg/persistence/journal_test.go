@@ -0,0 +1,104 @@
+package persistence_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/namreg/godown-v2/internal/pkg/persistence"
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+	"github.com/namreg/godown-v2/internal/pkg/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJournal(t *testing.T, opts persistence.Options) (*persistence.Journal, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal.aof")
+	j, err := persistence.Open(path, memory.New(nil), opts)
+	require.NoError(t, err)
+	return j, path
+}
+
+func TestJournal_PutAndReplay(t *testing.T) {
+	j, path := newJournal(t, persistence.Options{})
+
+	require.NoError(t, j.Put(storage.Key("a"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("1"), nil
+	}))
+	require.NoError(t, j.Put(storage.Key("b"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("2"), nil
+	}))
+	require.NoError(t, j.Del(storage.Key("a")))
+	require.NoError(t, j.Close())
+
+	replayed := memory.New(nil)
+	require.NoError(t, persistence.Replay(path, replayed))
+
+	_, err := replayed.Get(storage.Key("a"))
+	assert.Equal(t, storage.ErrKeyNotExists, err)
+
+	got, err := replayed.Get(storage.Key("b"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", got.String())
+}
+
+func TestJournal_ReplayStopsAtTruncatedTailRecord(t *testing.T) {
+	j, path := newJournal(t, persistence.Options{})
+
+	require.NoError(t, j.Put(storage.Key("complete"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("value"), nil
+	}))
+	require.NoError(t, j.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data[:len(data)-3], 0o600))
+
+	replayed := memory.New(nil)
+	require.NoError(t, persistence.Replay(path, replayed))
+
+	_, err = replayed.Get(storage.Key("complete"))
+	assert.Equal(t, storage.ErrKeyNotExists, err, "truncated record must not be applied")
+
+	all, err := replayed.All()
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func TestJournal_ReplayMissingFile(t *testing.T) {
+	replayed := memory.New(nil)
+	assert.NoError(t, persistence.Replay(filepath.Join(t.TempDir(), "missing.aof"), replayed))
+}
+
+func TestJournal_CompactionInterleavedWithConcurrentWrites(t *testing.T) {
+	j, path := newJournal(t, persistence.Options{SnapshotThreshold: 256})
+
+	const writers = 8
+	const perWriter = 25
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				key := storage.Key(string(rune('a'+w)) + string(rune('0'+i%10)))
+				err := j.Put(key, func(*storage.Value) (*storage.Value, error) {
+					return storage.NewStringValue("v"), nil
+				})
+				assert.NoError(t, err)
+			}
+		}(w)
+	}
+	wg.Wait()
+	require.NoError(t, j.Close())
+
+	replayed := memory.New(nil)
+	require.NoError(t, persistence.Replay(path, replayed))
+
+	keys, err := replayed.Keys()
+	require.NoError(t, err)
+	assert.NotEmpty(t, keys, "writes during compaction must not be lost")
+}
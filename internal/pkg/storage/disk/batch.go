@@ -0,0 +1,75 @@
+package disk
+
+import (
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+)
+
+// GetMany implements storage.Storage.
+func (s *Storage) GetMany(keys []storage.Key) (map[storage.Key]*storage.Value, []storage.Key, error) {
+	found := make(map[storage.Key]*storage.Value, len(keys))
+	var missing []storage.Key
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		items := tx.Bucket([]byte(itemsBucket))
+		for _, key := range keys {
+			val, err := getLive(items, key)
+			if err != nil {
+				return err
+			}
+			if val == nil {
+				missing = append(missing, key)
+				continue
+			}
+			found[key] = val
+		}
+		return nil
+	})
+	return found, missing, err
+}
+
+// PutMany implements storage.Storage, applying every setter inside a single
+// write transaction.
+func (s *Storage) PutMany(items map[storage.Key]storage.ValueSetter) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		itemsBkt := tx.Bucket([]byte(itemsBucket))
+		ttls := tx.Bucket([]byte(ttlBucket))
+
+		for key, setter := range items {
+			if err := putLocked(itemsBkt, ttls, key, setter); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DelMany implements storage.Storage.
+func (s *Storage) DelMany(keys ...storage.Key) (int, error) {
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		items := tx.Bucket([]byte(itemsBucket))
+		ttls := tx.Bucket([]byte(ttlBucket))
+
+		for _, key := range keys {
+			data := items.Get([]byte(key))
+			if data == nil {
+				continue
+			}
+			if val, err := storage.DecodeValue(data); err == nil {
+				if t, ok := val.TTL(); ok {
+					if err := ttls.Delete(ttlIndexKey(t, key)); err != nil {
+						return err
+					}
+				}
+			}
+			if err := items.Delete([]byte(key)); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
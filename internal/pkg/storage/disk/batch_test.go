@@ -0,0 +1,80 @@
+package disk
+
+import (
+	"testing"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_GetMany(t *testing.T) {
+	strg, _ := newStorage(t)
+
+	require.NoError(t, strg.Put(storage.Key("a"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("1"), nil
+	}))
+	require.NoError(t, strg.Put(storage.Key("b"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("2"), nil
+	}))
+
+	found, missing, err := strg.GetMany([]storage.Key{"a", "b", "missing"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[storage.Key]*storage.Value{
+		"a": storage.NewStringValue("1"),
+		"b": storage.NewStringValue("2"),
+	}, found)
+	assert.Equal(t, []storage.Key{"missing"}, missing)
+}
+
+func TestStorage_PutMany(t *testing.T) {
+	strg, _ := newStorage(t)
+
+	err := strg.PutMany(map[storage.Key]storage.ValueSetter{
+		"a": func(*storage.Value) (*storage.Value, error) { return storage.NewStringValue("1"), nil },
+		"b": func(*storage.Value) (*storage.Value, error) { return storage.NewStringValue("2"), nil },
+	})
+	require.NoError(t, err)
+
+	all, err := strg.All()
+	require.NoError(t, err)
+	assert.Equal(t, map[storage.Key]*storage.Value{
+		"a": storage.NewStringValue("1"),
+		"b": storage.NewStringValue("2"),
+	}, all)
+}
+
+func TestStorage_PutMany_RollsBackOnSetterError(t *testing.T) {
+	strg, _ := newStorage(t)
+
+	wantErr := assert.AnError
+	err := strg.PutMany(map[storage.Key]storage.ValueSetter{
+		"a": func(*storage.Value) (*storage.Value, error) { return storage.NewStringValue("1"), nil },
+		"b": func(*storage.Value) (*storage.Value, error) { return nil, wantErr },
+	})
+	assert.ErrorIs(t, err, wantErr)
+
+	all, err := strg.All()
+	require.NoError(t, err)
+	assert.Empty(t, all, "bbolt should abort the whole transaction on a setter error")
+}
+
+func TestStorage_DelMany(t *testing.T) {
+	strg, _ := newStorage(t)
+
+	require.NoError(t, strg.Put(storage.Key("a"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("1"), nil
+	}))
+	require.NoError(t, strg.Put(storage.Key("b"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("2"), nil
+	}))
+
+	deleted, err := strg.DelMany("a", "b", "missing")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	all, err := strg.All()
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
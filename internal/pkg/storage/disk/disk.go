@@ -0,0 +1,324 @@
+// Package disk provides a bbolt-backed implementation of storage.Storage
+// that persists data to a file so it survives process restarts.
+package disk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+)
+
+const (
+	dbFileName = "godown.db"
+
+	itemsBucket = "items"
+	ttlBucket   = "ttl_index"
+
+	reapInterval = time.Second
+)
+
+// Options configures a disk Storage.
+type Options struct {
+	// AutoCreate creates the target directory if it does not already exist.
+	AutoCreate bool
+	// SyncWrites fsyncs every write transaction to disk before it returns.
+	// Disabling it trades durability for throughput.
+	SyncWrites bool
+}
+
+// Storage is a storage.Storage implementation backed by a bbolt database
+// file. Keys and values live in a single bucket; a second bucket indexes
+// keys by expiration timestamp so TTL cleanup can scan it cheaply instead of
+// walking every item.
+type Storage struct {
+	db *bolt.DB
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New opens (or creates) a bbolt database rooted at dir and returns a
+// Storage backed by it.
+func New(dir string, opts Options) (*Storage, error) {
+	if opts.AutoCreate {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("disk: create dir %q: %w", dir, err)
+		}
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), 0o600, &bolt.Options{
+		NoSync: !opts.SyncWrites,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("disk: open db: %w", err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(itemsBucket)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists([]byte(ttlBucket))
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("disk: init buckets: %w", err)
+	}
+
+	s := &Storage{db: db, done: make(chan struct{})}
+	go s.reap()
+	return s, nil
+}
+
+// Close stops the background TTL reaper and closes the underlying database
+// file. It is safe to call multiple times.
+func (s *Storage) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.db.Close()
+	})
+	return err
+}
+
+// Put implements storage.Storage.
+func (s *Storage) Put(key storage.Key, setter storage.ValueSetter) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putLocked(tx.Bucket([]byte(itemsBucket)), tx.Bucket([]byte(ttlBucket)), key, setter)
+	})
+}
+
+// putLocked applies setter to key's current value within an already-open
+// write transaction, so Put and PutMany can share the same logic.
+func putLocked(items, ttls *bolt.Bucket, key storage.Key, setter storage.ValueSetter) error {
+	old, err := getLive(items, key)
+	if err != nil {
+		return err
+	}
+
+	newVal, err := setter(old)
+	if err != nil {
+		return err
+	}
+
+	// Drop the index entry of whatever is stored, even if it has expired and
+	// old is nil, so the reaper never deletes the value written below.
+	if data := items.Get([]byte(key)); data != nil {
+		if stored, err := storage.DecodeValue(data); err == nil {
+			if t, ok := stored.TTL(); ok {
+				if err := ttls.Delete(ttlIndexKey(t, key)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if newVal == nil {
+		return items.Delete([]byte(key))
+	}
+
+	data, err := storage.EncodeValue(newVal)
+	if err != nil {
+		return err
+	}
+	if err := items.Put([]byte(key), data); err != nil {
+		return err
+	}
+	if t, ok := newVal.TTL(); ok {
+		return ttls.Put(ttlIndexKey(t, key), []byte(key))
+	}
+	return nil
+}
+
+// Get implements storage.Storage.
+func (s *Storage) Get(key storage.Key) (*storage.Value, error) {
+	var val *storage.Value
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v, err := getLive(tx.Bucket([]byte(itemsBucket)), key)
+		val = v
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, storage.ErrKeyNotExists
+	}
+	return val, nil
+}
+
+// Del implements storage.Storage.
+func (s *Storage) Del(key storage.Key) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		items := tx.Bucket([]byte(itemsBucket))
+		ttls := tx.Bucket([]byte(ttlBucket))
+
+		if data := items.Get([]byte(key)); data != nil {
+			val, err := storage.DecodeValue(data)
+			if err == nil {
+				if t, ok := val.TTL(); ok {
+					if err := ttls.Delete(ttlIndexKey(t, key)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return items.Delete([]byte(key))
+	})
+}
+
+// Keys implements storage.Storage.
+func (s *Storage) Keys() ([]storage.Key, error) {
+	var keys []storage.Key
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(itemsBucket)).ForEach(func(k, data []byte) error {
+			val, err := storage.DecodeValue(data)
+			if err != nil {
+				return err
+			}
+			if val.IsExpired() {
+				return nil
+			}
+			keys = append(keys, storage.Key(k))
+			return nil
+		})
+	})
+	return keys, err
+}
+
+// All implements storage.Storage.
+func (s *Storage) All() (map[storage.Key]*storage.Value, error) {
+	all := make(map[storage.Key]*storage.Value)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(itemsBucket)).ForEach(func(k, data []byte) error {
+			val, err := storage.DecodeValue(data)
+			if err != nil {
+				return err
+			}
+			if val.IsExpired() {
+				return nil
+			}
+			all[storage.Key(k)] = val
+			return nil
+		})
+	})
+	return all, err
+}
+
+// AllWithTTL implements storage.Storage.
+func (s *Storage) AllWithTTL() (map[storage.Key]*storage.Value, error) {
+	all := make(map[storage.Key]*storage.Value)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		items := tx.Bucket([]byte(itemsBucket))
+		return tx.Bucket([]byte(ttlBucket)).ForEach(func(_, keyBytes []byte) error {
+			data := items.Get(keyBytes)
+			if data == nil {
+				return nil
+			}
+			val, err := storage.DecodeValue(data)
+			if err != nil {
+				return err
+			}
+			if val.IsExpired() {
+				return nil
+			}
+			all[storage.Key(keyBytes)] = val
+			return nil
+		})
+	})
+	return all, err
+}
+
+// Import copies every key/value pair from src into s, preserving TTLs. It is
+// meant for migrating a running memory.Storage onto disk.
+func (s *Storage) Import(src storage.Storage) error {
+	all, err := src.All()
+	if err != nil {
+		return fmt.Errorf("disk: import: read source: %w", err)
+	}
+	for key, val := range all {
+		v := val
+		if err := s.Put(key, func(*storage.Value) (*storage.Value, error) {
+			return v, nil
+		}); err != nil {
+			return fmt.Errorf("disk: import: put %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// reap periodically scans the ttl index bucket for expired keys and removes
+// them from both buckets.
+func (s *Storage) reap() {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.reapExpired()
+		}
+	}
+}
+
+func (s *Storage) reapExpired() {
+	_ = s.db.Update(func(tx *bolt.Tx) error {
+		items := tx.Bucket([]byte(itemsBucket))
+		ttls := tx.Bucket([]byte(ttlBucket))
+
+		now := nowKey(time.Now())
+		c := ttls.Cursor()
+		var expired [][]byte
+		for k, v := c.First(); k != nil && bytes.Compare(k, now) < 0; k, v = c.Next() {
+			expired = append(expired, append([]byte(nil), k...))
+			if err := items.Delete(v); err != nil {
+				return err
+			}
+		}
+		for _, k := range expired {
+			if err := ttls.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// getLive reads key from items, returning nil (not an error) if it is
+// absent or expired.
+func getLive(items *bolt.Bucket, key storage.Key) (*storage.Value, error) {
+	data := items.Get([]byte(key))
+	if data == nil {
+		return nil, nil
+	}
+	val, err := storage.DecodeValue(data)
+	if err != nil {
+		return nil, err
+	}
+	if val.IsExpired() {
+		return nil, nil
+	}
+	return val, nil
+}
+
+// ttlIndexKey builds a ttl_index bucket key that sorts by expiration time
+// first, so a forward cursor scan visits the soonest-expiring keys first.
+func ttlIndexKey(expireAt time.Time, key storage.Key) []byte {
+	b := nowKey(expireAt)
+	return append(b, key...)
+}
+
+func nowKey(t time.Time) []byte {
+	b := make([]byte, 8, 8+16)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
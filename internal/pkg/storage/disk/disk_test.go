@@ -0,0 +1,182 @@
+package disk
+
+import (
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+	"github.com/namreg/godown-v2/internal/pkg/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStorage(t *testing.T) (*Storage, string) {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := New(dir, Options{AutoCreate: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s, dir
+}
+
+func TestStorage_PutAndGet(t *testing.T) {
+	strg, _ := newStorage(t)
+
+	require.NoError(t, strg.Put(storage.Key("key"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("value"), nil
+	}))
+
+	got, err := strg.Get(storage.Key("key"))
+	require.NoError(t, err)
+	assert.Equal(t, "value", got.String())
+
+	_, err = strg.Get(storage.Key("missing"))
+	assert.Equal(t, storage.ErrKeyNotExists, err)
+}
+
+func TestStorage_Put_SetterError(t *testing.T) {
+	strg, _ := newStorage(t)
+
+	wantErr := assert.AnError
+	err := strg.Put(storage.Key("key"), func(*storage.Value) (*storage.Value, error) {
+		return nil, wantErr
+	})
+	assert.ErrorIs(t, err, wantErr)
+
+	_, err = strg.Get(storage.Key("key"))
+	assert.Equal(t, storage.ErrKeyNotExists, err)
+}
+
+func TestStorage_Put_NilValueDeletesKey(t *testing.T) {
+	strg, _ := newStorage(t)
+
+	require.NoError(t, strg.Put(storage.Key("key"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("value"), nil
+	}))
+	require.NoError(t, strg.Put(storage.Key("key"), func(*storage.Value) (*storage.Value, error) {
+		return nil, nil
+	}))
+
+	_, err := strg.Get(storage.Key("key"))
+	assert.Equal(t, storage.ErrKeyNotExists, err)
+}
+
+func TestStorage_Del(t *testing.T) {
+	strg, _ := newStorage(t)
+
+	require.NoError(t, strg.Put(storage.Key("key"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("value"), nil
+	}))
+	require.NoError(t, strg.Del(storage.Key("key")))
+
+	_, err := strg.Get(storage.Key("key"))
+	assert.Equal(t, storage.ErrKeyNotExists, err)
+
+	// deleting an absent key is not an error.
+	assert.NoError(t, strg.Del(storage.Key("missing")))
+}
+
+func TestStorage_Keys_All_AllWithTTL(t *testing.T) {
+	strg, _ := newStorage(t)
+
+	expired := storage.NewStringValue("expired_value")
+	expired.SetTTL(time.Now().Add(-1 * time.Second))
+
+	require.NoError(t, strg.Put(storage.Key("a"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("1"), nil
+	}))
+	require.NoError(t, strg.Put(storage.Key("b"), func(*storage.Value) (*storage.Value, error) {
+		val := storage.NewStringValue("2")
+		val.SetTTL(time.Now().Add(time.Hour))
+		return val, nil
+	}))
+	require.NoError(t, strg.Put(storage.Key("expired"), func(*storage.Value) (*storage.Value, error) {
+		return expired, nil
+	}))
+
+	keys, err := strg.Keys()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []storage.Key{"a", "b"}, keys)
+
+	all, err := strg.All()
+	require.NoError(t, err)
+	assert.Equal(t, map[storage.Key]*storage.Value{
+		"a": storage.NewStringValue("1"),
+		"b": all["b"],
+	}, all)
+
+	withTTL, err := strg.AllWithTTL()
+	require.NoError(t, err)
+	assert.Equal(t, map[storage.Key]*storage.Value{"b": all["b"]}, withTTL)
+}
+
+func TestStorage_PersistsAcrossReopen(t *testing.T) {
+	strg, dir := newStorage(t)
+
+	require.NoError(t, strg.Put(storage.Key("key"), func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("value"), nil
+	}))
+	require.NoError(t, strg.Close())
+
+	reopened, err := New(dir, Options{})
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	got, err := reopened.Get(storage.Key("key"))
+	require.NoError(t, err)
+	assert.Equal(t, "value", got.String())
+}
+
+func TestStorage_ActiveExpiration(t *testing.T) {
+	strg, _ := newStorage(t)
+
+	require.NoError(t, strg.Put(storage.Key("key"), func(*storage.Value) (*storage.Value, error) {
+		val := storage.NewStringValue("value")
+		val.SetTTL(time.Now().Add(20 * time.Millisecond))
+		return val, nil
+	}))
+
+	assert.Eventually(t, func() bool {
+		var present bool
+		err := strg.db.View(func(tx *bolt.Tx) error {
+			present = tx.Bucket([]byte(itemsBucket)).Get([]byte("key")) != nil
+			return nil
+		})
+		require.NoError(t, err)
+		return !present
+	}, 2*reapInterval, 10*time.Millisecond, "reaper should remove the expired key from the items bucket without a Get call")
+}
+
+func TestStorage_Close(t *testing.T) {
+	strg, _ := newStorage(t)
+	assert.NoError(t, strg.Close())
+	assert.NoError(t, strg.Close())
+}
+
+func TestStorage_Import(t *testing.T) {
+	src := memory.New(map[storage.Key]*storage.Value{
+		"a": storage.NewStringValue("1"),
+	})
+	defer src.Close()
+
+	withTTL := storage.NewStringValue("2")
+	withTTL.SetTTL(time.Now().Add(time.Hour))
+	require.NoError(t, src.Put(storage.Key("b"), func(*storage.Value) (*storage.Value, error) {
+		return withTTL, nil
+	}))
+
+	strg, _ := newStorage(t)
+	require.NoError(t, strg.Import(src))
+
+	got, err := strg.Get(storage.Key("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", got.String())
+
+	got, err = strg.Get(storage.Key("b"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", got.String())
+	_, ok := got.TTL()
+	assert.True(t, ok, "TTL must survive Import")
+}
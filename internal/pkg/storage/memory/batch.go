@@ -0,0 +1,63 @@
+package memory
+
+import "github.com/namreg/godown-v2/internal/pkg/storage"
+
+// GetMany implements storage.Storage.
+func (s *Storage) GetMany(keys []storage.Key) (map[storage.Key]*storage.Value, []storage.Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := make(map[storage.Key]*storage.Value, len(keys))
+	var missing []storage.Key
+	for _, key := range keys {
+		val, err := s.getLocked(key)
+		if err != nil {
+			s.stats.Misses++
+			missing = append(missing, key)
+			continue
+		}
+		s.stats.Hits++
+		if s.policy != nil {
+			s.policy.Touched(key)
+		}
+		found[key] = val
+	}
+	return found, missing, nil
+}
+
+// PutMany implements storage.Storage. Setters are applied atomically under a
+// single write lock: if any setter fails, every key this call touched
+// (including keys evicted to make room) is rolled back to its prior state
+// before the error is returned.
+func (s *Storage) PutMany(items map[storage.Key]storage.ValueSetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var undo []keySnapshot
+	for key, setter := range items {
+		if err := s.putWithEvictionLocked(key, setter, &undo); err != nil {
+			for i := len(undo) - 1; i >= 0; i-- {
+				s.restoreLocked(undo[i])
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// DelMany implements storage.Storage.
+func (s *Storage) DelMany(keys ...storage.Key) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for _, key := range keys {
+		if s.delLocked(key) {
+			deleted++
+		}
+		if s.policy != nil {
+			s.policy.Forgotten(key)
+		}
+	}
+	return deleted, nil
+}
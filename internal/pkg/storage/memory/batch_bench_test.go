@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+)
+
+func benchmarkStorage(n int) *Storage {
+	items := make(map[storage.Key]*storage.Value, n)
+	for i := 0; i < n; i++ {
+		items[storage.Key(strconv.Itoa(i))] = storage.NewStringValue("value")
+	}
+	return New(items)
+}
+
+func benchmarkKeys(n int) []storage.Key {
+	keys := make([]storage.Key, n)
+	for i := 0; i < n; i++ {
+		keys[i] = storage.Key(strconv.Itoa(i))
+	}
+	return keys
+}
+
+func BenchmarkStorage_Get_Serial_10(b *testing.B)   { benchmarkGetSerial(b, 10) }
+func BenchmarkStorage_Get_Serial_100(b *testing.B)  { benchmarkGetSerial(b, 100) }
+func BenchmarkStorage_Get_Serial_1000(b *testing.B) { benchmarkGetSerial(b, 1000) }
+
+func BenchmarkStorage_GetMany_10(b *testing.B)   { benchmarkGetMany(b, 10) }
+func BenchmarkStorage_GetMany_100(b *testing.B)  { benchmarkGetMany(b, 100) }
+func BenchmarkStorage_GetMany_1000(b *testing.B) { benchmarkGetMany(b, 1000) }
+
+func benchmarkGetSerial(b *testing.B, n int) {
+	strg := benchmarkStorage(n)
+	defer strg.Close()
+	keys := benchmarkKeys(n)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			if _, err := strg.Get(key); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func benchmarkGetMany(b *testing.B, n int) {
+	strg := benchmarkStorage(n)
+	defer strg.Close()
+	keys := benchmarkKeys(n)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := strg.GetMany(keys); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
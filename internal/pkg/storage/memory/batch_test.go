@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_GetMany(t *testing.T) {
+	expired := storage.NewStringValue("expired_value")
+	expired.SetTTL(time.Now().Add(-1 * time.Second))
+
+	strg := New(map[storage.Key]*storage.Value{
+		"a":       storage.NewStringValue("1"),
+		"b":       storage.NewStringValue("2"),
+		"expired": expired,
+	})
+	defer strg.Close()
+
+	found, missing, err := strg.GetMany([]storage.Key{"a", "b", "missing", "expired"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[storage.Key]*storage.Value{
+		"a": storage.NewStringValue("1"),
+		"b": storage.NewStringValue("2"),
+	}, found)
+	assertKeysEquals(t, []storage.Key{"missing", "expired"}, missing)
+}
+
+func TestStorage_PutMany(t *testing.T) {
+	strg := New(nil)
+	defer strg.Close()
+
+	err := strg.PutMany(map[storage.Key]storage.ValueSetter{
+		"a": func(*storage.Value) (*storage.Value, error) { return storage.NewStringValue("1"), nil },
+		"b": func(*storage.Value) (*storage.Value, error) { return storage.NewStringValue("2"), nil },
+	})
+	require.NoError(t, err)
+
+	all, err := strg.All()
+	require.NoError(t, err)
+	assert.Equal(t, map[storage.Key]*storage.Value{
+		"a": storage.NewStringValue("1"),
+		"b": storage.NewStringValue("2"),
+	}, all)
+}
+
+func TestStorage_PutMany_RollsBackOnSetterError(t *testing.T) {
+	strg := New(nil)
+	defer strg.Close()
+
+	wantErr := errors.New("setter failed")
+	err := strg.PutMany(map[storage.Key]storage.ValueSetter{
+		"a": func(*storage.Value) (*storage.Value, error) { return storage.NewStringValue("1"), nil },
+		"b": func(*storage.Value) (*storage.Value, error) { return nil, wantErr },
+	})
+	assert.ErrorIs(t, err, wantErr)
+
+	all, err := strg.All()
+	require.NoError(t, err)
+	assert.Empty(t, all, "no key from a failed batch should be committed")
+}
+
+func TestStorage_PutMany_RollsBackOnMaxEntriesReached(t *testing.T) {
+	strg := NewWithLimit(nil, 1, NoEviction{})
+	defer strg.Close()
+
+	err := strg.PutMany(map[storage.Key]storage.ValueSetter{
+		"a": func(*storage.Value) (*storage.Value, error) { return storage.NewStringValue("1"), nil },
+		"b": func(*storage.Value) (*storage.Value, error) { return storage.NewStringValue("2"), nil },
+	})
+	assert.ErrorIs(t, err, ErrMaxEntriesReached)
+
+	all, err := strg.All()
+	require.NoError(t, err)
+	assert.Empty(t, all, "a partially applied batch must not leave earlier keys committed")
+}
+
+func TestStorage_PutMany_RollsBackEvictionPolicyBookkeeping(t *testing.T) {
+	strg := NewWithLimit(nil, 2, AllKeysLRU())
+	defer strg.Close()
+
+	put := func(key storage.Key) error {
+		return strg.Put(key, func(*storage.Value) (*storage.Value, error) {
+			return storage.NewStringValue("v"), nil
+		})
+	}
+	require.NoError(t, put("a"))
+	require.NoError(t, put("b"))
+
+	wantErr := errors.New("setter failed")
+	err := strg.PutMany(map[storage.Key]storage.ValueSetter{
+		"c": func(*storage.Value) (*storage.Value, error) { return storage.NewStringValue("3"), nil },
+		"d": func(*storage.Value) (*storage.Value, error) { return nil, wantErr },
+	})
+	assert.ErrorIs(t, err, wantErr)
+
+	all, err := strg.All()
+	require.NoError(t, err)
+	assert.Equal(t, map[storage.Key]*storage.Value{
+		"a": storage.NewStringValue("v"),
+		"b": storage.NewStringValue("v"),
+	}, all)
+
+	// The rolled-back batch must also have restored the eviction policy's
+	// bookkeeping: a legitimate LRU victim still exists, so a subsequent Put
+	// on the still-full storage must succeed rather than get stuck behind
+	// the evicted-and-restored keys' stale tracking.
+	assert.NoError(t, put("e"))
+}
+
+func TestStorage_DelMany(t *testing.T) {
+	strg := New(map[storage.Key]*storage.Value{
+		"a": storage.NewStringValue("1"),
+		"b": storage.NewStringValue("2"),
+	})
+	defer strg.Close()
+
+	deleted, err := strg.DelMany("a", "b", "missing")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	all, err := strg.All()
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+)
+
+// ErrMaxEntriesReached is returned by Put/PutMany when the Storage is at its
+// entry limit and the configured EvictionPolicy declines to free up room
+// (as NoEviction always does).
+var ErrMaxEntriesReached = errors.New("memory: max entries reached")
+
+// lfuSampleSize is how many candidate keys AllKeysLFU inspects before
+// picking the least-frequently-used one, mirroring Redis' approximated LFU.
+const lfuSampleSize = 5
+
+// EvictionPolicy decides which key to free up when a bounded Storage is
+// full. Touched is called whenever a key is read or written, Forgotten
+// whenever it is removed (explicitly, by TTL, or by a previous eviction),
+// and Evict is asked to pick a victim when the Storage needs room for a new
+// key.
+type EvictionPolicy interface {
+	Touched(key storage.Key)
+	Forgotten(key storage.Key)
+	Evict(s *Storage) (key storage.Key, ok bool)
+}
+
+// NoEviction never frees up room; Put fails with ErrMaxEntriesReached once
+// the Storage is full.
+type NoEviction struct{}
+
+// Touched implements EvictionPolicy.
+func (NoEviction) Touched(storage.Key) {}
+
+// Forgotten implements EvictionPolicy.
+func (NoEviction) Forgotten(storage.Key) {}
+
+// Evict implements EvictionPolicy. It never evicts anything.
+func (NoEviction) Evict(*Storage) (storage.Key, bool) { return "", false }
+
+// lruPolicy is a doubly-linked-list LRU tracker shared by AllKeysLRU and
+// VolatileLRU; volatileOnly restricts eviction candidates to keys that
+// currently have a TTL.
+type lruPolicy struct {
+	mu           sync.Mutex
+	ll           *list.List
+	elems        map[storage.Key]*list.Element
+	volatileOnly bool
+}
+
+// AllKeysLRU evicts the least-recently touched key, regardless of whether
+// it has a TTL.
+func AllKeysLRU() EvictionPolicy {
+	return &lruPolicy{ll: list.New(), elems: make(map[storage.Key]*list.Element)}
+}
+
+// VolatileLRU evicts the least-recently touched key among those that have a
+// TTL set, leaving keys without a TTL untouched.
+func VolatileLRU() EvictionPolicy {
+	return &lruPolicy{ll: list.New(), elems: make(map[storage.Key]*list.Element), volatileOnly: true}
+}
+
+func (p *lruPolicy) Touched(key storage.Key) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.elems[key]; ok {
+		p.ll.MoveToFront(e)
+		return
+	}
+	p.elems[key] = p.ll.PushFront(key)
+}
+
+func (p *lruPolicy) Forgotten(key storage.Key) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.elems[key]; ok {
+		p.ll.Remove(e)
+		delete(p.elems, key)
+	}
+}
+
+func (p *lruPolicy) Evict(s *Storage) (storage.Key, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for e := p.ll.Back(); e != nil; e = e.Prev() {
+		key := e.Value.(storage.Key)
+		if p.volatileOnly {
+			if _, ok := s.itemsWithTTL[key]; !ok {
+				continue
+			}
+		}
+		return key, true
+	}
+	return "", false
+}
+
+// lfuPolicy approximates LFU the way Redis does: rather than keeping an
+// exact ordering, it samples a handful of keys and evicts whichever of them
+// was touched the fewest times.
+type lfuPolicy struct {
+	mu     sync.Mutex
+	counts map[storage.Key]uint64
+}
+
+// AllKeysLFU evicts the least-frequently touched key out of a small random
+// sample, regardless of whether it has a TTL.
+func AllKeysLFU() EvictionPolicy {
+	return &lfuPolicy{counts: make(map[storage.Key]uint64)}
+}
+
+func (p *lfuPolicy) Touched(key storage.Key) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counts[key]++
+}
+
+func (p *lfuPolicy) Forgotten(key storage.Key) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.counts, key)
+}
+
+func (p *lfuPolicy) Evict(s *Storage) (storage.Key, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		worstKey   storage.Key
+		worstCount uint64
+		found      bool
+		sampled    int
+	)
+	for key := range s.items {
+		count := p.counts[key]
+		if !found || count < worstCount {
+			worstKey, worstCount, found = key, count, true
+		}
+		sampled++
+		if sampled >= lfuSampleSize {
+			break
+		}
+	}
+	return worstKey, found
+}
+
+// randomPolicy evicts an arbitrary key, relying on Go's randomized map
+// iteration order.
+type randomPolicy struct{}
+
+// AllKeysRandom evicts an arbitrary key.
+func AllKeysRandom() EvictionPolicy { return randomPolicy{} }
+
+func (randomPolicy) Touched(storage.Key)   {}
+func (randomPolicy) Forgotten(storage.Key) {}
+
+func (randomPolicy) Evict(s *Storage) (storage.Key, bool) {
+	for key := range s.items {
+		return key, true
+	}
+	return "", false
+}
+
+// Stats reports cumulative hit/miss/eviction counters for a bounded Storage.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// Stats returns a snapshot of s's cumulative hit/miss/eviction counters.
+func (s *Storage) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stats
+}
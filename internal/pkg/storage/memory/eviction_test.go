@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_NoEviction_RejectsNewKeyWhenFull(t *testing.T) {
+	strg := NewWithLimit(nil, 2, NoEviction{})
+	defer strg.Close()
+
+	put := func(key storage.Key) error {
+		return strg.Put(key, func(*storage.Value) (*storage.Value, error) {
+			return storage.NewStringValue("v"), nil
+		})
+	}
+
+	require.NoError(t, put("a"))
+	require.NoError(t, put("b"))
+	assert.ErrorIs(t, put("c"), ErrMaxEntriesReached)
+
+	// Overwriting an existing key must still be allowed when full.
+	assert.NoError(t, put("a"))
+}
+
+func TestStorage_AllKeysLRU_EvictsLeastRecentlyTouched(t *testing.T) {
+	strg := NewWithLimit(nil, 2, AllKeysLRU())
+	defer strg.Close()
+
+	put := func(key storage.Key) error {
+		return strg.Put(key, func(*storage.Value) (*storage.Value, error) {
+			return storage.NewStringValue("v"), nil
+		})
+	}
+
+	require.NoError(t, put("a"))
+	require.NoError(t, put("b"))
+
+	// touch "a" so "b" becomes the least recently used.
+	_, err := strg.Get("a")
+	require.NoError(t, err)
+
+	require.NoError(t, put("c"))
+
+	_, err = strg.Get("b")
+	assert.Equal(t, storage.ErrKeyNotExists, err, "b should have been evicted")
+
+	_, err = strg.Get("a")
+	assert.NoError(t, err, "a was touched more recently and should survive")
+}
+
+func TestStorage_VolatileLRU_OnlyEvictsKeysWithTTL(t *testing.T) {
+	strg := NewWithLimit(nil, 2, VolatileLRU())
+	defer strg.Close()
+
+	require.NoError(t, strg.Put("persistent", func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("v"), nil
+	}))
+	require.NoError(t, strg.Put("volatile", func(*storage.Value) (*storage.Value, error) {
+		val := storage.NewStringValue("v")
+		val.SetTTL(time.Now().Add(time.Hour))
+		return val, nil
+	}))
+
+	require.NoError(t, strg.Put("new", func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("v"), nil
+	}))
+
+	_, err := strg.Get("persistent")
+	assert.NoError(t, err, "persistent has no TTL and must never be evicted by VolatileLRU")
+
+	_, err = strg.Get("volatile")
+	assert.Equal(t, storage.ErrKeyNotExists, err, "the only TTL-bearing key should have been evicted")
+}
+
+func TestStorage_Stats(t *testing.T) {
+	strg := NewWithLimit(nil, 1, AllKeysRandom())
+	defer strg.Close()
+
+	require.NoError(t, strg.Put("a", func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("v"), nil
+	}))
+	_, _ = strg.Get("a")
+	_, _ = strg.Get("missing")
+	require.NoError(t, strg.Put("b", func(*storage.Value) (*storage.Value, error) {
+		return storage.NewStringValue("v"), nil
+	}))
+
+	stats := strg.Stats()
+	assert.EqualValues(t, 1, stats.Hits)
+	assert.EqualValues(t, 1, stats.Misses)
+	assert.EqualValues(t, 1, stats.Evictions)
+}
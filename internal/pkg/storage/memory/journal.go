@@ -0,0 +1,18 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/namreg/godown-v2/internal/pkg/persistence"
+)
+
+// NewFromJournal creates a Storage and replays the append-only journal file
+// at path into it, reconstructing the state it held before a restart.
+func NewFromJournal(path string) (*Storage, error) {
+	s := New(nil)
+	if err := persistence.Replay(path, s); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("memory: replay journal: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,402 @@
+// Package memory provides an in-memory implementation of storage.Storage.
+package memory
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+)
+
+// reapInterval bounds how long the reaper goroutine sleeps when there is
+// nothing with a TTL to wait on.
+const reapInterval = time.Hour
+
+// Storage is an in-memory storage.Storage implementation. Keys with a TTL
+// are expired both lazily (on Get) and actively, by a background goroutine
+// that wakes up when the next key is due to expire.
+type Storage struct {
+	mu sync.RWMutex
+
+	items        map[storage.Key]*storage.Value
+	itemsWithTTL map[storage.Key]*storage.Value
+
+	ttl *ttlHeap
+
+	wake      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	maxEntries int
+	policy     EvictionPolicy
+	stats      Stats
+}
+
+// New creates a Storage pre-populated with items and starts its background
+// TTL reaper. Callers must call Close when the Storage is no longer needed.
+// The returned Storage has no entry limit; use NewWithLimit to bound it.
+func New(items map[storage.Key]*storage.Value) *Storage {
+	s := &Storage{
+		items:        make(map[storage.Key]*storage.Value, len(items)),
+		itemsWithTTL: make(map[storage.Key]*storage.Value),
+		ttl:          newTTLHeap(),
+		wake:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	for k, v := range items {
+		s.items[k] = v
+		if t, ok := v.TTL(); ok {
+			s.itemsWithTTL[k] = v
+			heap.Push(s.ttl, &ttlHeapItem{key: k, expireAt: t})
+		}
+	}
+	go s.reap()
+	return s
+}
+
+// NewWithLimit creates a Storage like New, but bounded to at most maxEntries
+// keys. Once full, policy decides which key to evict to make room for a new
+// one; with NoEviction, Put instead fails with ErrMaxEntriesReached.
+func NewWithLimit(items map[storage.Key]*storage.Value, maxEntries int, policy EvictionPolicy) *Storage {
+	s := New(items)
+	s.maxEntries = maxEntries
+	s.policy = policy
+	for k := range s.items {
+		policy.Touched(k)
+	}
+	return s
+}
+
+// Close stops the background reaper goroutine. It is safe to call multiple
+// times.
+func (s *Storage) Close() error {
+	s.closeOnce.Do(func() {
+		if s.done != nil {
+			close(s.done)
+		}
+	})
+	return nil
+}
+
+// Put implements storage.Storage.
+func (s *Storage) Put(key storage.Key, setter storage.ValueSetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.putWithEvictionLocked(key, setter, nil)
+}
+
+// keySnapshot captures a key's state before it is mutated, so a failed
+// multi-key operation (PutMany) can restore it.
+type keySnapshot struct {
+	key     storage.Key
+	existed bool
+	val     *storage.Value
+}
+
+// snapshotLocked captures key's current state. Callers must hold s.mu.
+func (s *Storage) snapshotLocked(key storage.Key) keySnapshot {
+	val, existed := s.items[key]
+	return keySnapshot{key: key, existed: existed, val: val}
+}
+
+// restoreLocked undoes a mutation recorded by snapshotLocked, re-syncing the
+// eviction policy's bookkeeping (Touched/Forgotten) with the restored items
+// state so a rolled-back PutMany doesn't leave the policy out of sync with
+// reality. Callers must hold s.mu for writing.
+func (s *Storage) restoreLocked(snap keySnapshot) {
+	if !snap.existed {
+		delete(s.items, snap.key)
+		delete(s.itemsWithTTL, snap.key)
+		s.ttlRemove(snap.key)
+	} else {
+		s.items[snap.key] = snap.val
+		if t, ok := snap.val.TTL(); ok {
+			s.itemsWithTTL[snap.key] = snap.val
+			s.ttlUpsert(snap.key, t)
+		} else {
+			delete(s.itemsWithTTL, snap.key)
+			s.ttlRemove(snap.key)
+		}
+	}
+
+	if s.policy == nil {
+		return
+	}
+	if snap.existed {
+		s.policy.Touched(snap.key)
+	} else {
+		s.policy.Forgotten(snap.key)
+	}
+}
+
+// putWithEvictionLocked makes room for key if the Storage is bounded and
+// full, applies setter, then lets the eviction policy know key was touched
+// (or forgotten, if setter deleted it). Callers must hold s.mu for writing.
+//
+// When undo is non-nil, every key this call mutates (including any evicted
+// to make room) is snapshotted onto it first, so the caller can roll the
+// whole operation back on a later failure.
+func (s *Storage) putWithEvictionLocked(key storage.Key, setter storage.ValueSetter, undo *[]keySnapshot) error {
+	if s.policy != nil && s.maxEntries > 0 {
+		if _, existed := s.items[key]; !existed {
+			for len(s.items) >= s.maxEntries {
+				victim, ok := s.policy.Evict(s)
+				if !ok {
+					return ErrMaxEntriesReached
+				}
+				if undo != nil {
+					*undo = append(*undo, s.snapshotLocked(victim))
+				}
+				s.evictLocked(victim)
+			}
+		}
+	}
+
+	if undo != nil {
+		*undo = append(*undo, s.snapshotLocked(key))
+	}
+	if err := s.putLocked(key, setter); err != nil {
+		return err
+	}
+
+	if s.policy != nil {
+		if _, ok := s.items[key]; ok {
+			s.policy.Touched(key)
+		} else {
+			s.policy.Forgotten(key)
+		}
+	}
+	return nil
+}
+
+// evictLocked removes key to free up room for a new entry. Callers must
+// hold s.mu for writing.
+func (s *Storage) evictLocked(key storage.Key) {
+	delete(s.items, key)
+	delete(s.itemsWithTTL, key)
+	s.ttlRemove(key)
+	if s.policy != nil {
+		s.policy.Forgotten(key)
+	}
+	s.stats.Evictions++
+}
+
+// putLocked is Put's body, without eviction bookkeeping. Callers must hold
+// s.mu for writing.
+func (s *Storage) putLocked(key storage.Key, setter storage.ValueSetter) error {
+	old := s.items[key]
+	if old != nil && old.IsExpired() {
+		old = nil
+	}
+
+	newVal, err := setter(old)
+	if err != nil {
+		return err
+	}
+	if newVal == nil {
+		delete(s.items, key)
+		delete(s.itemsWithTTL, key)
+		s.ttlRemove(key)
+		return nil
+	}
+
+	s.items[key] = newVal
+	if t, ok := newVal.TTL(); ok {
+		s.itemsWithTTL[key] = newVal
+		s.ttlUpsert(key, t)
+	} else {
+		delete(s.itemsWithTTL, key)
+		s.ttlRemove(key)
+	}
+	return nil
+}
+
+// Get implements storage.Storage.
+func (s *Storage) Get(key storage.Key) (*storage.Value, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	val, err := s.getLocked(key)
+	if err != nil {
+		s.stats.Misses++
+		return nil, err
+	}
+	s.stats.Hits++
+	if s.policy != nil {
+		s.policy.Touched(key)
+	}
+	return val, nil
+}
+
+// getLocked is Get's body. Callers must hold s.mu for writing (it may
+// lazily delete an expired entry).
+func (s *Storage) getLocked(key storage.Key) (*storage.Value, error) {
+	val, ok := s.items[key]
+	if !ok {
+		return nil, storage.ErrKeyNotExists
+	}
+	if val.IsExpired() {
+		delete(s.items, key)
+		delete(s.itemsWithTTL, key)
+		s.ttlRemove(key)
+		if s.policy != nil {
+			s.policy.Forgotten(key)
+		}
+		return nil, storage.ErrKeyNotExists
+	}
+	return val, nil
+}
+
+// Del implements storage.Storage.
+func (s *Storage) Del(key storage.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delLocked(key)
+	if s.policy != nil {
+		s.policy.Forgotten(key)
+	}
+	return nil
+}
+
+// delLocked is Del's body. Callers must hold s.mu for writing. It reports
+// whether key actually existed, which DelMany uses to count deletions.
+func (s *Storage) delLocked(key storage.Key) bool {
+	_, existed := s.items[key]
+	delete(s.items, key)
+	delete(s.itemsWithTTL, key)
+	s.ttlRemove(key)
+	return existed
+}
+
+// Keys implements storage.Storage. It trusts the background reaper to have
+// already removed expired entries from items, so it does not filter them.
+func (s *Storage) Keys() ([]storage.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]storage.Key, 0, len(s.items))
+	for k := range s.items {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// All implements storage.Storage. It trusts the background reaper to have
+// already removed expired entries from items, so it does not filter them.
+func (s *Storage) All() (map[storage.Key]*storage.Value, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make(map[storage.Key]*storage.Value, len(s.items))
+	for k, v := range s.items {
+		all[k] = v
+	}
+	return all, nil
+}
+
+// AllWithTTL implements storage.Storage. It trusts the background reaper to
+// have already removed expired entries from itemsWithTTL, so it does not
+// filter them.
+func (s *Storage) AllWithTTL() (map[storage.Key]*storage.Value, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make(map[storage.Key]*storage.Value, len(s.itemsWithTTL))
+	for k, v := range s.itemsWithTTL {
+		all[k] = v
+	}
+	return all, nil
+}
+
+// ttlUpsert records that key now expires at expireAt, adding it to the heap
+// or repositioning it if already present. Callers must hold s.mu.
+func (s *Storage) ttlUpsert(key storage.Key, expireAt time.Time) {
+	if s.ttl == nil {
+		s.ttl = newTTLHeap()
+	}
+	if idx, ok := s.ttl.index[key]; ok {
+		s.ttl.items[idx].expireAt = expireAt
+		heap.Fix(s.ttl, idx)
+	} else {
+		heap.Push(s.ttl, &ttlHeapItem{key: key, expireAt: expireAt})
+	}
+	s.notifyReaper()
+}
+
+// ttlRemove removes key from the heap, if present. Callers must hold s.mu.
+func (s *Storage) ttlRemove(key storage.Key) {
+	if s.ttl == nil {
+		return
+	}
+	idx, ok := s.ttl.index[key]
+	if !ok {
+		return
+	}
+	heap.Remove(s.ttl, idx)
+}
+
+// notifyReaper wakes up the reap goroutine so it can reconsider how long to
+// sleep for. It never blocks.
+func (s *Storage) notifyReaper() {
+	if s.wake == nil {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// reap sleeps until the next key is due to expire, then removes every
+// expired key. It is woken early whenever a sooner deadline is inserted.
+func (s *Storage) reap() {
+	timer := time.NewTimer(reapInterval)
+	defer timer.Stop()
+
+	for {
+		s.mu.RLock()
+		d := reapInterval
+		if s.ttl.Len() > 0 {
+			if until := time.Until(s.ttl.items[0].expireAt); until < d {
+				d = until
+			}
+		}
+		s.mu.RUnlock()
+
+		if d < 0 {
+			d = 0
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(d)
+
+		select {
+		case <-s.done:
+			return
+		case <-s.wake:
+		case <-timer.C:
+			s.reapExpired()
+		}
+	}
+}
+
+// reapExpired deletes every key whose TTL has passed.
+func (s *Storage) reapExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for s.ttl.Len() > 0 && !s.ttl.items[0].expireAt.After(now) {
+		item := heap.Pop(s.ttl).(*ttlHeapItem)
+		delete(s.items, item.key)
+		delete(s.itemsWithTTL, item.key)
+		if s.policy != nil {
+			s.policy.Forgotten(item.key)
+		}
+	}
+}
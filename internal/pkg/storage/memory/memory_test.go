@@ -225,14 +225,10 @@ func TestStorage_Del(t *testing.T) {
 }
 
 func TestStorage_Keys(t *testing.T) {
-	expired := storage.NewStringValue("expired_value")
-	expired.SetTTL(time.Now().Add(-1 * time.Second))
-
 	strg := Storage{
 		items: map[storage.Key]*storage.Value{
-			storage.Key("key"):     storage.NewStringValue("value"),
-			storage.Key("key2"):    storage.NewStringValue("value2"),
-			storage.Key("expired"): expired,
+			storage.Key("key"):  storage.NewStringValue("value"),
+			storage.Key("key2"): storage.NewStringValue("value2"),
 		},
 	}
 	expected := []storage.Key{storage.Key("key"), storage.Key("key2")}
@@ -279,6 +275,56 @@ func TestStorage_AllWithTTL(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestStorage_ActiveExpiration(t *testing.T) {
+	val := storage.NewStringValue("value")
+	val.SetTTL(time.Now().Add(20 * time.Millisecond))
+
+	strg := New(map[storage.Key]*storage.Value{"key": val})
+	defer strg.Close()
+
+	assert.Eventually(t, func() bool {
+		strg.mu.RLock()
+		defer strg.mu.RUnlock()
+		_, ok := strg.items["key"]
+		return !ok
+	}, time.Second, 5*time.Millisecond, "expired key should be reaped without a Get call")
+}
+
+func TestStorage_ActiveExpiration_WakesOnSoonerDeadline(t *testing.T) {
+	strg := New(nil)
+	defer strg.Close()
+
+	assert.NoError(t, strg.Put(storage.Key("far"), func(*storage.Value) (*storage.Value, error) {
+		val := storage.NewStringValue("far")
+		val.SetTTL(time.Now().Add(time.Hour))
+		return val, nil
+	}))
+
+	assert.NoError(t, strg.Put(storage.Key("soon"), func(*storage.Value) (*storage.Value, error) {
+		val := storage.NewStringValue("soon")
+		val.SetTTL(time.Now().Add(20 * time.Millisecond))
+		return val, nil
+	}))
+
+	assert.Eventually(t, func() bool {
+		strg.mu.RLock()
+		defer strg.mu.RUnlock()
+		_, ok := strg.items["soon"]
+		return !ok
+	}, time.Second, 5*time.Millisecond, "the sooner deadline should be reaped before the hour-long one fires")
+
+	strg.mu.RLock()
+	_, ok := strg.items["far"]
+	strg.mu.RUnlock()
+	assert.True(t, ok)
+}
+
+func TestStorage_Close(t *testing.T) {
+	strg := New(nil)
+	assert.NoError(t, strg.Close())
+	assert.NoError(t, strg.Close())
+}
+
 func assertKeysEquals(t *testing.T, a, b []storage.Key) bool {
 	sort.Slice(a, func(i, j int) bool {
 		return a[i] < a[j]
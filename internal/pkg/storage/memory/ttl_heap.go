@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/namreg/godown-v2/internal/pkg/storage"
+)
+
+// ttlHeapItem is a single (key, expiration) pair tracked by ttlHeap.
+type ttlHeapItem struct {
+	key      storage.Key
+	expireAt time.Time
+}
+
+// ttlHeap is a container/heap.Interface implementation that keeps keys with
+// a TTL ordered by expiration time, soonest first. It also keeps an index of
+// key to its current slot so Put/Del can update or remove an entry in
+// O(log n) instead of scanning the whole heap.
+type ttlHeap struct {
+	items []*ttlHeapItem
+	index map[storage.Key]int
+}
+
+func newTTLHeap() *ttlHeap {
+	return &ttlHeap{index: make(map[storage.Key]int)}
+}
+
+func (h *ttlHeap) Len() int { return len(h.items) }
+
+func (h *ttlHeap) Less(i, j int) bool {
+	return h.items[i].expireAt.Before(h.items[j].expireAt)
+}
+
+func (h *ttlHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.index[h.items[i].key] = i
+	h.index[h.items[j].key] = j
+}
+
+func (h *ttlHeap) Push(x interface{}) {
+	item := x.(*ttlHeapItem)
+	h.index[item.key] = len(h.items)
+	h.items = append(h.items, item)
+}
+
+func (h *ttlHeap) Pop() interface{} {
+	old := h.items
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	h.items = old[:n-1]
+	delete(h.index, item.key)
+	return item
+}
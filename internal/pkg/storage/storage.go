@@ -0,0 +1,58 @@
+// Package storage defines the key/value storage abstraction used by godown
+// and the value types it operates on. Concrete implementations live in
+// sibling packages such as "memory".
+package storage
+
+import "errors"
+
+// Key identifies a value inside a Storage.
+type Key string
+
+// ErrKeyNotExists is returned by Get when the requested key is absent or has
+// expired.
+var ErrKeyNotExists = errors.New("key does not exist")
+
+// ValueSetter computes the new value for a key given its current value (nil
+// if the key does not exist or has expired). Returning a nil value with a
+// nil error instructs the Storage to delete the key.
+type ValueSetter func(old *Value) (new *Value, err error)
+
+// Storage is the contract every godown storage backend must satisfy.
+type Storage interface {
+	// Put atomically applies setter to the current value stored under key
+	// and stores the result.
+	Put(key Key, setter ValueSetter) error
+
+	// Get returns the value stored under key, or ErrKeyNotExists if it does
+	// not exist or has expired.
+	Get(key Key) (*Value, error)
+
+	// Del removes key. It is not an error to delete a key that does not exist.
+	Del(key Key) error
+
+	// GetMany returns the values found for keys in a single lock acquisition,
+	// together with the subset of keys that were missing or expired.
+	GetMany(keys []Key) (found map[Key]*Value, missing []Key, err error)
+
+	// PutMany applies every setter in items under a single write lock, so
+	// readers never observe only part of the batch applied.
+	PutMany(items map[Key]ValueSetter) error
+
+	// DelMany removes every given key in a single lock acquisition and
+	// reports how many of them actually existed.
+	DelMany(keys ...Key) (deleted int, err error)
+
+	// Keys returns all non-expired keys currently stored.
+	Keys() ([]Key, error)
+
+	// All returns every non-expired key/value pair currently stored.
+	All() (map[Key]*Value, error)
+
+	// AllWithTTL returns every non-expired key/value pair that has a TTL set.
+	AllWithTTL() (map[Key]*Value, error)
+
+	// Close releases any resources held by the Storage (background
+	// goroutines, open files, etc). After Close returns, the Storage must
+	// not be used.
+	Close() error
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+)
+
+// Kind identifies the concrete type of data held by a Value.
+type Kind int
+
+// Supported value kinds.
+const (
+	KindString Kind = iota
+	KindList
+)
+
+// Value wraps a piece of data stored under a Key, together with an optional
+// expiration time.
+type Value struct {
+	kind Kind
+	str  string
+	list []string
+	ttl  *time.Time
+}
+
+// NewStringValue creates a Value holding a string.
+func NewStringValue(s string) *Value {
+	return &Value{kind: KindString, str: s}
+}
+
+// NewListValue creates a Value holding a list of strings.
+func NewListValue(items ...string) *Value {
+	return &Value{kind: KindList, list: items}
+}
+
+// Kind reports the concrete type of data held by v.
+func (v *Value) Kind() Kind {
+	return v.kind
+}
+
+// String returns the string held by v, or "" if v does not hold a string.
+func (v *Value) String() string {
+	return v.str
+}
+
+// List returns the list of strings held by v, or nil if v does not hold a list.
+func (v *Value) List() []string {
+	return v.list
+}
+
+// SetTTL sets the time at which v expires.
+func (v *Value) SetTTL(t time.Time) {
+	v.ttl = &t
+}
+
+// TTL returns the expiration time of v and whether one is set.
+func (v *Value) TTL() (time.Time, bool) {
+	if v.ttl == nil {
+		return time.Time{}, false
+	}
+	return *v.ttl, true
+}
+
+// IsExpired reports whether v has a TTL set that is in the past.
+func (v *Value) IsExpired() bool {
+	t, ok := v.TTL()
+	return ok && time.Now().After(t)
+}
+
+// valueGob mirrors Value's unexported fields so they can be gob-encoded by
+// other packages (e.g. persistent storage backends) without exporting them
+// on Value itself.
+type valueGob struct {
+	Kind Kind
+	Str  string
+	List []string
+	TTL  *time.Time
+}
+
+// GobEncode implements gob.GobEncoder so a Value can be persisted to disk or
+// to an append-only journal.
+func (v *Value) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(valueGob{
+		Kind: v.kind,
+		Str:  v.str,
+		List: v.list,
+		TTL:  v.ttl,
+	}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder, the inverse of GobEncode.
+func (v *Value) GobDecode(data []byte) error {
+	var g valueGob
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&g); err != nil {
+		return err
+	}
+	v.kind = g.Kind
+	v.str = g.Str
+	v.list = g.List
+	v.ttl = g.TTL
+	return nil
+}
+
+// EncodeValue gob-encodes v for storage backends that persist values as raw
+// bytes (e.g. a journal record or a bbolt value).
+func EncodeValue(v *Value) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeValue is the inverse of EncodeValue.
+func DecodeValue(data []byte) (*Value, error) {
+	v := &Value{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}